@@ -0,0 +1,71 @@
+package kite
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEvent describes one method call handled by a Kite: who made it, how
+// they authenticated, what it was, and how it went.
+type AuditEvent struct {
+	Time       time.Time     `json:"time"`
+	RemoteKite string        `json:"remoteKite,omitempty"`
+	Username   string        `json:"username,omitempty"`
+	Method     string        `json:"method"`
+	AuthType   string        `json:"authType,omitempty"`
+	TokenID    string        `json:"tokenId,omitempty"` // the token's "jti" claim, if any
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	ArgSize    int           `json:"argSize"`
+}
+
+// AuditLogger receives an AuditEvent for every method call a Kite handles,
+// once the call has completed.
+type AuditLogger interface {
+	Emit(AuditEvent)
+}
+
+// AuditLoggerFunc adapts a plain function to the AuditLogger interface, for
+// callers who'd rather ship events to a SIEM with a callback than a file.
+type AuditLoggerFunc func(AuditEvent)
+
+// Emit calls f(e).
+func (f AuditLoggerFunc) Emit(e AuditEvent) { f(e) }
+
+// FileAuditLogger writes one JSON object per line to a file, the format
+// most SIEM log collectors expect.
+type FileAuditLogger struct {
+	mu sync.Mutex
+	w  *os.File
+}
+
+// NewFileAuditLogger returns a FileAuditLogger that appends JSON-lines
+// encoded events to the file at path, creating it if necessary.
+func NewFileAuditLogger(path string) (*FileAuditLogger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAuditLogger{w: f}, nil
+}
+
+// Emit writes e as a single line of JSON. Encoding errors are written to
+// stderr rather than returned, since AuditLogger.Emit has no error return.
+func (l *FileAuditLogger) Emit(e AuditEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := json.NewEncoder(l.w).Encode(e); err != nil {
+		fmt.Fprintf(os.Stderr, "kite: failed to write audit event: %s\n", err)
+	}
+}
+
+// Close closes the underlying file.
+func (l *FileAuditLogger) Close() error {
+	return l.w.Close()
+}
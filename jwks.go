@@ -0,0 +1,215 @@
+package kite
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// KiteClaims are the JWT claims carried by a kite access token, whether
+// kontrol or a trusted third-party OIDC provider minted it. It mirrors
+// kitekey.KiteClaims.
+type KiteClaims struct {
+	jwt.StandardClaims
+
+	// Scope is a space-separated list of granted scopes, following the
+	// OAuth2 convention.
+	Scope string `json:"scope,omitempty"`
+
+	// Scp and Roles are alternative, array-shaped ways issuers encode
+	// grants; some OIDC providers use "scp", others "roles".
+	Scp   []string `json:"scp,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// jwksCacheEntry holds a fetched JWKS document along with the time it
+// becomes stale, derived from the response's Cache-Control header.
+type jwksCacheEntry struct {
+	keys    map[string]interface{} // kid -> *rsa.PublicKey or ed25519.PublicKey
+	expires time.Time
+}
+
+// jwksHTTPClient bounds how long a JWKS fetch may block. It runs
+// synchronously on the authentication path of every request whose key isn't
+// already cached, so a slow or unresponsive issuer must not be able to hang
+// that path indefinitely.
+var jwksHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// jwksCache fetches and caches JSON Web Key Sets published by trusted OIDC
+// issuers, keyed by issuer URL.
+type jwksCache struct {
+	mu      sync.Mutex
+	entries map[string]*jwksCacheEntry
+}
+
+func newJWKSCache() *jwksCache {
+	return &jwksCache{entries: make(map[string]*jwksCacheEntry)}
+}
+
+// trustedIssuerJWKS caches JWKS documents for all Kites in this process.
+// Issuers are global (whitelisted by URL), so there's no need to keep a
+// separate cache per *Kite.
+var trustedIssuerJWKS = newJWKSCache()
+
+// keyForIssuer returns the public key identified by kid in the issuer's
+// JWKS document, fetching and caching the document as needed.
+func (c *jwksCache) keyForIssuer(issuer, kid string) (interface{}, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[issuer]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		var err error
+		entry, err = c.fetch(issuer)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.entries[issuer] = entry
+		c.mu.Unlock()
+	}
+
+	key, ok := entry.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("kite: no key with kid %q in JWKS of issuer %q", kid, issuer)
+	}
+
+	return key, nil
+}
+
+// fetch downloads and parses the JWKS document served at the issuer's
+// well-known endpoint.
+func (c *jwksCache) fetch(issuer string) (*jwksCacheEntry, error) {
+	url := strings.TrimRight(issuer, "/") + "/.well-known/jwks.json"
+
+	resp, err := jwksHTTPClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("kite: failed to fetch JWKS from %q: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kite: unexpected status fetching JWKS from %q: %s", url, resp.Status)
+	}
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("kite: failed to decode JWKS from %q: %s", url, err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue // Skip key types we don't understand yet.
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return &jwksCacheEntry{
+		keys:    keys,
+		expires: time.Now().Add(jwksMaxAge(resp.Header.Get("Cache-Control"))),
+	}, nil
+}
+
+// jwksMaxAge parses the max-age directive of a Cache-Control header,
+// falling back to a conservative default when it's missing or malformed.
+func jwksMaxAge(cacheControl string) time.Duration {
+	const defaultMaxAge = 5 * time.Minute
+
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err != nil || seconds <= 0 {
+			return defaultMaxAge
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	return defaultMaxAge
+}
+
+// jsonWebKey is the subset of RFC 7517 fields needed to reconstruct RSA and
+// Ed25519 public keys from a JWKS document.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("kite: unsupported JWK key type %q", k.Kty)
+	}
+}
+
+// trustedIssuerKeyfunc returns a jwt.Keyfunc that resolves the verification
+// key for a token with no "iss" claim from the local kite's own trusted key
+// set (see KeySet), and for a token whose issuer is present in
+// k.TrustedIssuers from that issuer's JWKS document. A token naming any
+// other issuer is rejected.
+func (k *Kite) trustedIssuerKeyfunc(tokenString string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		claims, ok := token.Claims.(*KiteClaims)
+		if !ok || claims.Issuer == "" {
+			return k.keySetKeyfunc(tokenString)(token)
+		}
+
+		for _, issuer := range k.TrustedIssuers {
+			if issuer != claims.Issuer {
+				continue
+			}
+
+			kid, _ := token.Header["kid"].(string)
+			if kid == "" {
+				return nil, fmt.Errorf("kite: token from issuer %q has no kid header", claims.Issuer)
+			}
+
+			return trustedIssuerJWKS.keyForIssuer(claims.Issuer, kid)
+		}
+
+		return nil, fmt.Errorf("kite: untrusted issuer: %s", claims.Issuer)
+	}
+}
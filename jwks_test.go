@@ -0,0 +1,238 @@
+package kite
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func jwkFromRSA(kid string, pub *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func newJWKSServer(t *testing.T, cacheControl string, keys ...jsonWebKey) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		json.NewEncoder(w).Encode(struct {
+			Keys []jsonWebKey `json:"keys"`
+		}{Keys: keys})
+	}))
+}
+
+func TestTrustedIssuerKeyfuncAcceptsTrustedIssuer(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	srv := newJWKSServer(t, "", jwkFromRSA("issuer-key", &priv.PublicKey))
+	defer srv.Close()
+	defer func() { trustedIssuerJWKS = newJWKSCache() }()
+
+	k := &Kite{TrustedIssuers: []string{srv.URL}}
+
+	claims := &KiteClaims{StandardClaims: jwt.StandardClaims{Issuer: srv.URL}}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "issuer-key"
+	signed, err := token.SignedString(priv)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	parsed, err := jwt.ParseWithClaims(signed, &KiteClaims{}, k.trustedIssuerKeyfunc(signed))
+	if err != nil || !parsed.Valid {
+		t.Fatalf("ParseWithClaims() = %v, valid=%v; want a valid token from a trusted issuer", err, parsed.Valid)
+	}
+}
+
+func TestTrustedIssuerKeyfuncRejectsUntrustedIssuer(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	k := &Kite{TrustedIssuers: []string{"https://trusted.example.com"}}
+
+	claims := &KiteClaims{StandardClaims: jwt.StandardClaims{Issuer: "https://untrusted.example.com"}}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "some-key"
+	signed, err := token.SignedString(priv)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	if _, err := jwt.ParseWithClaims(signed, &KiteClaims{}, k.trustedIssuerKeyfunc(signed)); err == nil {
+		t.Fatal("ParseWithClaims() from an untrusted issuer succeeded, want an error")
+	}
+}
+
+func TestJWKSCacheRespectsMaxAge(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Cache-Control", "max-age=3600")
+		json.NewEncoder(w).Encode(struct {
+			Keys []jsonWebKey `json:"keys"`
+		}{Keys: []jsonWebKey{jwkFromRSA("key-1", &priv.PublicKey)}})
+	}))
+	defer srv.Close()
+
+	cache := newJWKSCache()
+
+	if _, err := cache.keyForIssuer(srv.URL, "key-1"); err != nil {
+		t.Fatalf("keyForIssuer() error = %v", err)
+	}
+	if _, err := cache.keyForIssuer(srv.URL, "key-1"); err != nil {
+		t.Fatalf("keyForIssuer() error = %v", err)
+	}
+
+	if fetches != 1 {
+		t.Fatalf("server saw %d fetches for two lookups within max-age, want 1 (cache not respected)", fetches)
+	}
+}
+
+func TestJWKSCacheZeroMaxAgeUsesDefaultTTL(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Cache-Control", "max-age=0")
+		json.NewEncoder(w).Encode(struct {
+			Keys []jsonWebKey `json:"keys"`
+		}{Keys: []jsonWebKey{jwkFromRSA("key-1", &priv.PublicKey)}})
+	}))
+	defer srv.Close()
+
+	cache := newJWKSCache()
+
+	if _, err := cache.keyForIssuer(srv.URL, "key-1"); err != nil {
+		t.Fatalf("keyForIssuer() error = %v", err)
+	}
+	// A zero/negative max-age falls back to jwksMaxAge's conservative
+	// default rather than expiring immediately, so this still reads from
+	// cache rather than forcing a second round trip.
+	if _, err := cache.keyForIssuer(srv.URL, "key-1"); err != nil {
+		t.Fatalf("keyForIssuer() error = %v", err)
+	}
+
+	if fetches != 1 {
+		t.Fatalf("server saw %d fetches, want 1 (max-age=0 should fall back to the default TTL, not force a refetch)", fetches)
+	}
+}
+
+func TestJWKSMaxAge(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		want         time.Duration
+	}{
+		{"max-age=60", 60 * time.Second},
+		{"public, max-age=120", 120 * time.Second},
+		{"no-cache", 5 * time.Minute},
+		{"", 5 * time.Minute},
+		{"max-age=bogus", 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := jwksMaxAge(tt.cacheControl); got != tt.want {
+			t.Errorf("jwksMaxAge(%q) = %v, want %v", tt.cacheControl, got, tt.want)
+		}
+	}
+}
+
+func TestJSONWebKeyPublicKey(t *testing.T) {
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	rsaKey, err := jwkFromRSA("rsa-key", &rsaPriv.PublicKey).publicKey()
+	if err != nil {
+		t.Fatalf("publicKey() error for RSA key = %v", err)
+	}
+	if _, ok := rsaKey.(*rsa.PublicKey); !ok {
+		t.Errorf("publicKey() for kty=RSA returned %T, want *rsa.PublicKey", rsaKey)
+	}
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	okp := jsonWebKey{Kty: "OKP", Kid: "okp-key", X: base64.RawURLEncoding.EncodeToString(edPub)}
+	edKey, err := okp.publicKey()
+	if err != nil {
+		t.Fatalf("publicKey() error for OKP key = %v", err)
+	}
+	if got, ok := edKey.(ed25519.PublicKey); !ok || !got.Equal(edPub) {
+		t.Errorf("publicKey() for kty=OKP = %v, want %v", edKey, edPub)
+	}
+
+	if _, err := (jsonWebKey{Kty: "EC", Kid: "unsupported"}).publicKey(); err == nil {
+		t.Error("publicKey() for an unsupported kty succeeded, want an error")
+	}
+}
+
+func TestJWKSFetchSkipsUnsupportedKeyTypes(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	srv := newJWKSServer(t, "max-age=3600",
+		jsonWebKey{Kty: "EC", Kid: "unsupported-key"},
+		jwkFromRSA("rsa-key", &priv.PublicKey),
+	)
+	defer srv.Close()
+
+	cache := newJWKSCache()
+
+	if _, err := cache.keyForIssuer(srv.URL, "unsupported-key"); err == nil {
+		t.Error("keyForIssuer() found a key of an unsupported kty, want it skipped")
+	}
+	if _, err := cache.keyForIssuer(srv.URL, "rsa-key"); err != nil {
+		t.Errorf("keyForIssuer(rsa-key) error = %v, want the RSA key alongside the skipped one", err)
+	}
+}
+
+func TestJWKSFetchTimesOut(t *testing.T) {
+	t.Cleanup(func() {
+		jwksHTTPClient = &http.Client{Timeout: 10 * time.Second}
+	})
+	jwksHTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	cache := newJWKSCache()
+	if _, err := cache.keyForIssuer(srv.URL, "any-kid"); err == nil {
+		t.Error("keyForIssuer() against an unresponsive issuer succeeded, want a timeout error")
+	}
+}
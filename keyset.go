@@ -0,0 +1,193 @@
+package kite
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keySetEntry is a single trusted signing key and whether it has been
+// retired.
+type keySetEntry struct {
+	key       *rsa.PublicKey
+	retired   bool
+	retiredAt time.Time
+}
+
+// KeySet is the set of RSA public keys a Kite trusts to verify tokens,
+// indexed by the JWT "kid" header. It lets kontrol publish a new signing
+// key ahead of a rotation and keep accepting tokens signed by the outgoing
+// key for an overlap window, instead of every client needing to update in
+// lockstep.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]*keySetEntry
+}
+
+// NewKeySet returns an empty KeySet.
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*keySetEntry)}
+}
+
+// retire, lookup, all and empty all treat a nil *KeySet as an empty one, so
+// a zero-value Kite (KeySet never assigned) behaves like one with no
+// rotated keys instead of panicking. rotate has no nil check: its only
+// caller, RotateKey, creates the KeySet first.
+
+func (s *KeySet) rotate(kid string, key *rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[kid] = &keySetEntry{key: key}
+}
+
+func (s *KeySet) retire(kid string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.keys[kid]; ok {
+		e.retired = true
+		e.retiredAt = time.Now()
+	}
+}
+
+func (s *KeySet) lookup(kid string) (key *rsa.PublicKey, retired, found bool) {
+	if s == nil {
+		return nil, false, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.keys[kid]
+	if !ok {
+		return nil, false, false
+	}
+
+	return e.key, e.retired, true
+}
+
+// all returns a snapshot of every trusted key, retired or not, keyed by
+// kid, for the migration fallback path below.
+func (s *KeySet) all() map[string]*rsa.PublicKey {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make(map[string]*rsa.PublicKey, len(s.keys))
+	for kid, e := range s.keys {
+		keys[kid] = e.key
+	}
+
+	return keys
+}
+
+// empty reports whether s has no trusted keys at all, which is also true
+// of a nil *KeySet (the zero value of Kite.KeySet).
+func (s *KeySet) empty() bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.keys) == 0
+}
+
+// RotateKey adds (or replaces) the trusted key for kid. Tokens whose "kid"
+// header matches kid will verify against key. It lazily initializes
+// k.KeySet, so it's safe to call on a Kite that's never had one before;
+// k.keySetMu guards that lazy init against concurrent first calls, since
+// KeySet itself has nothing to lock until it exists.
+func (k *Kite) RotateKey(kid string, key *rsa.PublicKey) {
+	k.keySetMu.Lock()
+	if k.KeySet == nil {
+		k.KeySet = NewKeySet()
+	}
+	k.keySetMu.Unlock()
+
+	k.KeySet.rotate(kid, key)
+}
+
+// RetireKey marks kid as retired. Tokens signed with it still verify, so
+// in-flight tokens don't break, but AuthenticateFromToken logs a warning
+// each time one does, so operators know when it's safe to drop the key
+// for good.
+func (k *Kite) RetireKey(kid string) {
+	k.KeySet.retire(kid)
+}
+
+// keySetKeyfunc returns a jwt.Keyfunc that resolves the verification key
+// by the token's "kid" header, falling back to k.RSAKey when k.KeySet has
+// no trusted keys yet. See keySetOrFallbackKeyfunc.
+func (k *Kite) keySetKeyfunc(tokenString string) jwt.Keyfunc {
+	return k.keySetOrFallbackKeyfunc(tokenString, k.RSAKey)
+}
+
+// keySetOrFallbackKeyfunc returns a jwt.Keyfunc that resolves the
+// verification key by the token's "kid" header. If k.KeySet has no trusted
+// keys at all -- the state of every Kite that hasn't called RotateKey yet
+// -- it calls fallback instead, so upgrading to this package doesn't
+// require an operator to migrate their existing key into a KeySet before
+// the first token verifies again. AuthenticateFromToken falls back to
+// k.RSAKey; AuthenticateFromKiteKey falls back to kitekey.GetKontrolKey,
+// since kite keys are always minted by kontrol. Once KeySet has at least
+// one key, lookups go through it: tokens with no "kid" header (predating
+// rotation) fall back to trying every currently trusted key in turn.
+// Either path logs a warning if the token verifies against a retired key.
+func (k *Kite) keySetOrFallbackKeyfunc(tokenString string, fallback jwt.Keyfunc) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if k.KeySet.empty() {
+			return fallback(token)
+		}
+
+		if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+			key, retired, found := k.KeySet.lookup(kid)
+			if !found {
+				return nil, fmt.Errorf("kite: unknown signing key kid %q", kid)
+			}
+
+			if retired {
+				parts := strings.Split(tokenString, ".")
+				if len(parts) == 3 && token.Method.Verify(parts[0]+"."+parts[1], parts[2], key) == nil {
+					k.Log.Warning("kite: token verified against retired key %q", kid)
+				}
+			}
+
+			return key, nil
+		}
+
+		parts := strings.Split(tokenString, ".")
+		if len(parts) != 3 {
+			return nil, errors.New("kite: malformed token")
+		}
+		signingInput := parts[0] + "." + parts[1]
+
+		for kid, key := range k.KeySet.all() {
+			if err := token.Method.Verify(signingInput, parts[2], key); err != nil {
+				continue
+			}
+
+			if _, retired, _ := k.KeySet.lookup(kid); retired {
+				k.Log.Warning("kite: token verified against retired key %q", kid)
+			}
+
+			return key, nil
+		}
+
+		return nil, errors.New("kite: no trusted key verifies this token")
+	}
+}
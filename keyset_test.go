@@ -0,0 +1,145 @@
+package kite
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"sync"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, &KiteClaims{})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	return signed
+}
+
+func TestKeySetEmptyAndNil(t *testing.T) {
+	var nilSet *KeySet
+	if !nilSet.empty() {
+		t.Error("nil *KeySet.empty() = false, want true")
+	}
+	if key, retired, found := nilSet.lookup("kid"); key != nil || retired || found {
+		t.Errorf("nil *KeySet.lookup() = %v, %v, %v; want nil, false, false", key, retired, found)
+	}
+	nilSet.retire("kid") // must not panic
+
+	set := NewKeySet()
+	if !set.empty() {
+		t.Error("new KeySet.empty() = false, want true")
+	}
+}
+
+func TestKeySetRotateAndRetire(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	k := &Kite{}
+	k.RotateKey("key-1", &priv.PublicKey)
+
+	if k.KeySet.empty() {
+		t.Fatal("KeySet.empty() = true after RotateKey, want false")
+	}
+
+	key, retired, found := k.KeySet.lookup("key-1")
+	if !found || retired || key != &priv.PublicKey {
+		t.Errorf("lookup(key-1) = %v, %v, %v; want the rotated key, not retired, found", key, retired, found)
+	}
+
+	k.RetireKey("key-1")
+	_, retired, found = k.KeySet.lookup("key-1")
+	if !found || !retired {
+		t.Errorf("lookup(key-1) after RetireKey: retired=%v found=%v; want both true", retired, found)
+	}
+}
+
+func TestRotateKeyConcurrentFirstCalls(t *testing.T) {
+	k := &Kite{}
+
+	const n = 20
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		kid := string(rune('a' + i))
+		go func() {
+			defer wg.Done()
+			k.RotateKey(kid, &priv.PublicKey)
+		}()
+	}
+	wg.Wait()
+
+	if got := len(k.KeySet.all()); got != n {
+		t.Fatalf("KeySet has %d keys after %d concurrent RotateKey calls, want %d (a racing lazy-init must have dropped some)", got, n, n)
+	}
+}
+
+func TestKeySetKeyfuncKidPresent(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	k := &Kite{}
+	k.RotateKey("key-1", &priv.PublicKey)
+
+	tokenString := signToken(t, priv, "key-1")
+	claims := &KiteClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, k.keySetKeyfunc(tokenString))
+	if err != nil || !token.Valid {
+		t.Fatalf("ParseWithClaims() = %v, valid=%v; want a valid token", err, token.Valid)
+	}
+}
+
+func TestKeySetKeyfuncUnknownKid(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	k := &Kite{}
+	k.RotateKey("key-1", &priv.PublicKey)
+
+	tokenString := signToken(t, priv, "unknown-kid")
+	if _, err := jwt.ParseWithClaims(tokenString, &KiteClaims{}, k.keySetKeyfunc(tokenString)); err == nil {
+		t.Fatal("ParseWithClaims() with unknown kid succeeded, want an error")
+	}
+}
+
+func TestKeySetKeyfuncKidAbsentTriesAllKeys(t *testing.T) {
+	otherPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signingPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	k := &Kite{}
+	k.RotateKey("other-key", &otherPriv.PublicKey)
+	k.RotateKey("signing-key", &signingPriv.PublicKey)
+
+	// Signed with no "kid" header, as tokens predating key rotation would be.
+	tokenString := signToken(t, signingPriv, "")
+	token, err := jwt.ParseWithClaims(tokenString, &KiteClaims{}, k.keySetKeyfunc(tokenString))
+	if err != nil || !token.Valid {
+		t.Fatalf("ParseWithClaims() = %v, valid=%v; want the token to verify against signing-key", err, token.Valid)
+	}
+}
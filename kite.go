@@ -0,0 +1,75 @@
+package kite
+
+import (
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/koding/kite/protocol"
+	"github.com/koding/logging"
+)
+
+// Config holds the options a Kite is configured with.
+//
+// This only lists the fields this package's authentication/authorization
+// code reads; the rest of Kite's configuration (kontrol URL, kite key
+// path, environment, ...) lives alongside it but isn't reproduced here.
+type Config struct {
+	// DisableAuthentication skips Request.authenticate() entirely. Only
+	// meant for trusted, same-process Kites.
+	DisableAuthentication bool
+
+	// KontrolKey is kontrol's RSA public key in PEM format, used by
+	// Kite.RSAKey to verify kontrol-issued kite keys.
+	KontrolKey string
+}
+
+// Kite is the local Kite: the struct HandleFunc, AuthenticateFromToken and
+// friends are methods on. Connection setup, the dnode server, and service
+// registration with kontrol live in the rest of the package; this
+// declaration only carries the fields authentication and authorization
+// depend on.
+type Kite struct {
+	Config *Config
+	Log    logging.Logger
+
+	handlers       map[string]HandlerFunc
+	Authenticators map[string]func(*Request) error
+
+	// TrustedIssuers is the whitelist of OIDC issuers AuthenticateFromToken
+	// accepts tokens from, besides this Kite's own trusted key(s). See
+	// trustedIssuerKeyfunc.
+	TrustedIssuers []string
+
+	// ClaimsValidator, if set, is given a final say over a token's claims
+	// after signature and standard claim validation succeed.
+	ClaimsValidator func(*jwt.Token, *Request) error
+
+	// KeySet holds the RSA keys trusted to verify tokens this Kite itself
+	// (or kontrol, on its behalf) issued, indexed by "kid" so a signing
+	// key can be rotated without breaking tokens already in flight. See
+	// RotateKey, RetireKey and keySetKeyfunc.
+	KeySet   *KeySet
+	keySetMu sync.Mutex
+
+	// RevocationStore, if set, is consulted after signature validation so
+	// a compromised token can be rejected before its exp. See
+	// RegisterRevocationMethods and OnRevoke.
+	RevocationStore RevocationStore
+
+	// AuditLogger, if set, receives an AuditEvent for every method call
+	// this Kite handles.
+	AuditLogger AuditLogger
+
+	preHandlers     []HandlerFunc
+	postHandlers    []PostHandlerFunc
+	revokeListeners []func(RevokedToken)
+}
+
+// Client represents a connection to a remote Kite. As with Kite above,
+// this only carries the fields this package's request handling depends on.
+type Client struct {
+	LocalKite *Kite
+	Kite      protocol.Kite
+
+	firstRequestHandlersNotified sync.Once
+}
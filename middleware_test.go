@@ -0,0 +1,90 @@
+package kite
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGrantedScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *KiteClaims
+		want   []string
+	}{
+		{"nil claims grants nothing", nil, nil},
+		{
+			"space-separated scope claim",
+			&KiteClaims{Scope: "kite.admin kite.read"},
+			[]string{"kite.admin", "kite.read"},
+		},
+		{
+			"scp array claim",
+			&KiteClaims{Scp: []string{"kite.admin", "kite.read"}},
+			[]string{"kite.admin", "kite.read"},
+		},
+		{
+			"roles array claim",
+			&KiteClaims{Roles: []string{"kite.admin"}},
+			[]string{"kite.admin"},
+		},
+		{
+			"all three shapes combine",
+			&KiteClaims{Scope: "kite.read", Scp: []string{"kite.write"}, Roles: []string{"kite.admin"}},
+			[]string{"kite.admin", "kite.read", "kite.write"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			granted := grantedScopes(tt.claims)
+
+			var got []string
+			for scope := range granted {
+				got = append(got, scope)
+			}
+			sort.Strings(got)
+			sort.Strings(tt.want)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("grantedScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	claims := &KiteClaims{Scope: "kite.read", Scp: []string{"kite.write"}, Roles: []string{"kite.admin"}}
+
+	if missing := missingScopes(claims, []string{"kite.read", "kite.write", "kite.admin"}); len(missing) != 0 {
+		t.Errorf("missingScopes() = %v, want none", missing)
+	}
+
+	missing := missingScopes(claims, []string{"kite.read", "kite.superadmin"})
+	if want := []string{"kite.superadmin"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missingScopes() = %v, want %v", missing, want)
+	}
+
+	if missing := missingScopes(nil, []string{"kite.read"}); !reflect.DeepEqual(missing, []string{"kite.read"}) {
+		t.Errorf("missingScopes(nil, ...) = %v, want every required scope reported missing", missing)
+	}
+}
+
+func TestHandleFuncWithScopesDisableAuthentication(t *testing.T) {
+	k := &Kite{Config: &Config{}, handlers: make(map[string]HandlerFunc)}
+	k.HandleFuncWithScopes("kite.admin.only", []string{"kite.admin"}, func(r *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	r := &Request{LocalKite: k}
+
+	if _, err := k.handlers["kite.admin.only"](r); err == nil {
+		t.Fatal("handler with missing scope and authentication enabled succeeded, want an authorizationError")
+	}
+
+	k.Config.DisableAuthentication = true
+	result, err := k.handlers["kite.admin.only"](r)
+	if err != nil || result != "ok" {
+		t.Fatalf("handler with DisableAuthentication set = %v, %v; want \"ok\", nil", result, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/koding/kite/dnode"
@@ -18,6 +19,11 @@ type Request struct {
 	Client         *Client
 	Username       string
 	Authentication *Authentication
+
+	// Claims holds the parsed JWT claims of the authenticated token, once
+	// authenticate() has run. It is nil until then and for requests that
+	// bypass authentication (DisableAuthentication or an outgoing Client).
+	Claims *KiteClaims
 }
 
 // Response is the type of the object that is returned from request handlers
@@ -47,6 +53,8 @@ func (c *Client) runMethod(method string, handlerFunc HandlerFunc, args *dnode.P
 		callback dnode.Function
 	)
 
+	start := time.Now()
+
 	// Send result if "responseCallback" exists in the request.
 	defer func() {
 		if callback.Caller == nil {
@@ -65,6 +73,44 @@ func (c *Client) runMethod(method string, handlerFunc HandlerFunc, args *dnode.P
 		}
 	}()
 
+	// Emit an audit event once kiteErr has its final value, whether the
+	// call succeeded, failed, or panicked.
+	defer func() {
+		if c.LocalKite.AuditLogger == nil {
+			return
+		}
+
+		event := AuditEvent{
+			Time:       start,
+			RemoteKite: c.Kite.ID,
+			Method:     method,
+			Success:    kiteErr == nil,
+			Latency:    time.Since(start),
+		}
+
+		if request != nil {
+			event.Username = request.Username
+			if request.Authentication != nil {
+				event.AuthType = request.Authentication.Type
+			}
+			if request.Claims != nil {
+				event.TokenID = request.Claims.Id
+			}
+			// request.Args is the handler's actual argument, unlike args
+			// above, which is the whole dnode call envelope (auth token,
+			// response callback, kite identity, and all).
+			if request.Args != nil {
+				event.ArgSize = len(request.Args.Raw)
+			}
+		}
+
+		if kiteErr != nil {
+			event.Error = kiteErr.Message
+		}
+
+		c.LocalKite.AuditLogger.Emit(event)
+	}()
+
 	// Recover dnode argument errors. The caller can use functions like
 	// MustString(), MustSlice()... without the fear of panic.
 	defer c.LocalKite.recoverError(&kiteErr)()
@@ -78,10 +124,24 @@ func (c *Client) runMethod(method string, handlerFunc HandlerFunc, args *dnode.P
 		}
 	}
 
-	// Call the handler function.
+	// Call the handler function, wrapped by any registered pre/post
+	// middleware, in registration order.
 	var err error
+	for _, pre := range c.LocalKite.preHandlers {
+		if result, err = pre(request); err != nil {
+			panic(err) // This will be recoverd from kite.recoverError() above.
+		}
+	}
+
 	result, err = handlerFunc(request)
 
+	// Post handlers see the real (result, err) the method handler produced
+	// -- including an error -- so they can observe or transform it (e.g.
+	// for metrics/audit logging) instead of only ever replacing it.
+	for _, post := range c.LocalKite.postHandlers {
+		result, err = post(request, result, err)
+	}
+
 	if err != nil {
 		panic(err) // This will be recoverd from kite.recoverError() above.
 	}
@@ -167,9 +227,25 @@ func (r *Request) authenticate() *Error {
 	return nil
 }
 
-// AuthenticateFromToken is the default Authenticator for Kite.
+// audienceMatches reports whether aud, a token's "aud" claim, authorizes the
+// token for the kite identified by kiteID. An empty aud never matches -- a
+// token with no audience at all must not be treated as valid for every kite.
+func audienceMatches(kiteID, aud string) bool {
+	return aud != "" && strings.HasPrefix(kiteID, aud)
+}
+
+// AuthenticateFromToken is the default Authenticator for Kite. Besides
+// tokens signed by the local kite's own key, it accepts tokens signed by a
+// third-party OIDC provider as long as the token's issuer is present in
+// Kite.TrustedIssuers; the verification key for those is fetched from the
+// issuer's JWKS endpoint and cached. Tokens verified against the local key
+// must carry no "iss" claim; a token naming an issuer outside
+// Kite.TrustedIssuers is rejected. If Kite.ClaimsValidator is set, it is
+// given a final say over the parsed claims before the request is accepted.
 func (k *Kite) AuthenticateFromToken(r *Request) error {
-	token, err := jwt.Parse(r.Authentication.Key, r.LocalKite.RSAKey)
+	claims := &KiteClaims{}
+
+	token, err := jwt.ParseWithClaims(r.Authentication.Key, claims, k.trustedIssuerKeyfunc(r.Authentication.Key))
 	if err != nil {
 		return err
 	}
@@ -178,24 +254,45 @@ func (k *Kite) AuthenticateFromToken(r *Request) error {
 		return errors.New("Invalid signature in token")
 	}
 
-	if audience, ok := token.Claims["aud"].(string); !ok || !strings.HasPrefix(k.Kite().String(), audience) {
-		return fmt.Errorf("Invalid audience in token: %s", audience)
+	if k.RevocationStore != nil {
+		if revoked, err := k.RevocationStore.IsRevoked(claims.Id); err != nil {
+			return err
+		} else if revoked {
+			return errors.New("Token has been revoked")
+		}
 	}
 
 	// We don't check for exp and nbf claims here because jwt-go package already checks them.
 
-	if username, ok := token.Claims["sub"].(string); !ok {
+	if !audienceMatches(k.Kite().String(), claims.Audience) {
+		return fmt.Errorf("Invalid audience in token: %s", claims.Audience)
+	}
+
+	if claims.Subject == "" {
 		return errors.New("Username is not present in token")
-	} else {
-		r.Username = username
 	}
 
+	if k.ClaimsValidator != nil {
+		if err := k.ClaimsValidator(token, r); err != nil {
+			return err
+		}
+	}
+
+	r.Claims = claims
+	r.Username = claims.Subject
+
 	return nil
 }
 
-// AuthenticateFromKiteKey authenticates user from kite key.
+// AuthenticateFromKiteKey authenticates user from kite key. Like
+// AuthenticateFromToken, it verifies against k.KeySet by "kid" once a key
+// has been rotated in, falling back to kontrol's well-known key -- rather
+// than k.RSAKey -- since kite keys are always minted by kontrol, never a
+// trusted third-party issuer.
 func (k *Kite) AuthenticateFromKiteKey(r *Request) error {
-	token, err := jwt.Parse(r.Authentication.Key, kitekey.GetKontrolKey)
+	claims := &KiteClaims{}
+
+	token, err := jwt.ParseWithClaims(r.Authentication.Key, claims, k.keySetOrFallbackKeyfunc(r.Authentication.Key, kitekey.GetKontrolKey))
 	if err != nil {
 		return err
 	}
@@ -204,11 +301,26 @@ func (k *Kite) AuthenticateFromKiteKey(r *Request) error {
 		return errors.New("Invalid signature in token")
 	}
 
-	if username, ok := token.Claims["sub"].(string); !ok {
+	if k.RevocationStore != nil {
+		if revoked, err := k.RevocationStore.IsRevoked(claims.Id); err != nil {
+			return err
+		} else if revoked {
+			return errors.New("Token has been revoked")
+		}
+	}
+
+	if claims.Subject == "" {
 		return errors.New("Username is not present in token")
-	} else {
-		r.Username = username
 	}
 
+	if k.ClaimsValidator != nil {
+		if err := k.ClaimsValidator(token, r); err != nil {
+			return err
+		}
+	}
+
+	r.Claims = claims
+	r.Username = claims.Subject
+
 	return nil
 }
@@ -0,0 +1,26 @@
+package kite
+
+import "testing"
+
+func TestAudienceMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		kiteID string
+		aud    string
+		want   bool
+	}{
+		{"empty aud never matches", "kite://myapp/1.0.0/hostname/abc", "", false},
+		{"exact match", "kite://myapp/1.0.0/hostname/abc", "kite://myapp/1.0.0/hostname/abc", true},
+		{"aud is a prefix of kiteID", "kite://myapp/1.0.0/hostname/abc", "kite://myapp", true},
+		{"aud is not a prefix", "kite://myapp/1.0.0/hostname/abc", "kite://otherapp", false},
+		{"aud longer than kiteID", "kite://myapp", "kite://myapp/1.0.0/hostname/abc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := audienceMatches(tt.kiteID, tt.aud); got != tt.want {
+				t.Errorf("audienceMatches(%q, %q) = %v, want %v", tt.kiteID, tt.aud, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package kite
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// RevokedToken describes a single revoked kite token.
+type RevokedToken struct {
+	JTI   string
+	Until time.Time
+}
+
+// RevocationStore is consulted by AuthenticateFromToken and
+// AuthenticateFromKiteKey, right after signature validation succeeds, so a
+// compromised kite key can be invalidated before its exp. Implementations
+// must be safe for concurrent use.
+type RevocationStore interface {
+	// IsRevoked reports whether the token identified by jti is currently
+	// revoked.
+	IsRevoked(jti string) (bool, error)
+
+	// Revoke marks jti as revoked until the given time. A zero until
+	// revokes jti for good.
+	Revoke(jti string, until time.Time) error
+
+	// List returns every currently revoked token.
+	List() ([]RevokedToken, error)
+}
+
+// MemoryRevocationStore is an in-memory RevocationStore. It's the simplest
+// option for a single-process kite or for tests; multi-process deployments
+// should use BoltRevocationStore or RedisRevocationStore so every process
+// observes the same revocations.
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevocationStore returns an empty MemoryRevocationStore.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: make(map[string]time.Time)}
+}
+
+func (s *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.RLock()
+	until, ok := s.revoked[jti]
+	s.mu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	if !until.IsZero() && time.Now().After(until) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s *MemoryRevocationStore) Revoke(jti string, until time.Time) error {
+	s.mu.Lock()
+	s.revoked[jti] = until
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryRevocationStore) List() ([]RevokedToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	tokens := make([]RevokedToken, 0, len(s.revoked))
+	for jti, until := range s.revoked {
+		if !until.IsZero() && now.After(until) {
+			continue // Revocation has expired.
+		}
+		tokens = append(tokens, RevokedToken{JTI: jti, Until: until})
+	}
+
+	return tokens, nil
+}
+
+// RegisterRevocationMethods exposes "kite.revoke" and "kite.listRevoked" on
+// k, each requiring the "kite.admin" scope, so a control plane can push and
+// audit revocations without direct datastore access. A revocation is only
+// visible to processes sharing k.RevocationStore (e.g. every kite pointed
+// at the same Redis/BoltDB instance); see OnRevoke to fan a revocation out
+// to processes that don't.
+func (k *Kite) RegisterRevocationMethods() {
+	k.HandleFuncWithScopes("kite.revoke", []string{"kite.admin"}, k.handleRevoke)
+	k.HandleFuncWithScopes("kite.listRevoked", []string{"kite.admin"}, k.handleListRevoked)
+}
+
+// OnRevoke registers a listener that's called with every token revoked
+// through the "kite.revoke" method, after it's been written to
+// k.RevocationStore. This package registers no listener itself; a kite
+// whose RevocationStore isn't shared sees a revocation only if a listener
+// registered here delivers it.
+func (k *Kite) OnRevoke(listener func(RevokedToken)) {
+	k.revokeListeners = append(k.revokeListeners, listener)
+}
+
+func (k *Kite) handleRevoke(r *Request) (interface{}, error) {
+	if k.RevocationStore == nil {
+		return nil, errors.New("kite: no RevocationStore configured")
+	}
+
+	var args struct {
+		JTI   string    `json:"jti"`
+		Until time.Time `json:"until"`
+	}
+	r.Args.One().MustUnmarshal(&args)
+
+	if args.JTI == "" {
+		return nil, errors.New("kite: jti is required")
+	}
+
+	if err := k.RevocationStore.Revoke(args.JTI, args.Until); err != nil {
+		return nil, err
+	}
+
+	revoked := RevokedToken{JTI: args.JTI, Until: args.Until}
+	for _, listener := range k.revokeListeners {
+		listener(revoked)
+	}
+
+	return true, nil
+}
+
+func (k *Kite) handleListRevoked(r *Request) (interface{}, error) {
+	if k.RevocationStore == nil {
+		return nil, errors.New("kite: no RevocationStore configured")
+	}
+
+	return k.RevocationStore.List()
+}
@@ -0,0 +1,102 @@
+package kite
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var revocationBucket = []byte("revoked")
+
+// BoltRevocationStore is a RevocationStore backed by a BoltDB file, for
+// single-host deployments that want revocations to survive a restart
+// without standing up a separate datastore.
+type BoltRevocationStore struct {
+	db *bolt.DB
+}
+
+// NewBoltRevocationStore opens (creating if necessary) the revocation
+// bucket in the BoltDB database at path.
+func NewBoltRevocationStore(path string) (*BoltRevocationStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(revocationBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltRevocationStore{db: db}, nil
+}
+
+func (s *BoltRevocationStore) IsRevoked(jti string) (bool, error) {
+	var until time.Time
+	var found bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(revocationBucket).Get([]byte(jti))
+		if v == nil {
+			return nil
+		}
+
+		found = true
+		return until.UnmarshalBinary(v)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	if !until.IsZero() && time.Now().After(until) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s *BoltRevocationStore) Revoke(jti string, until time.Time) error {
+	v, err := until.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(revocationBucket).Put([]byte(jti), v)
+	})
+}
+
+func (s *BoltRevocationStore) List() ([]RevokedToken, error) {
+	var tokens []RevokedToken
+	now := time.Now()
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(revocationBucket).ForEach(func(k, v []byte) error {
+			var until time.Time
+			if err := until.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			if !until.IsZero() && now.After(until) {
+				return nil // Revocation has expired.
+			}
+
+			tokens = append(tokens, RevokedToken{JTI: string(k), Until: until})
+			return nil
+		})
+	})
+
+	return tokens, err
+}
+
+// Close releases the underlying BoltDB file handle.
+func (s *BoltRevocationStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,78 @@
+package kite
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// RedisRevocationStore is a RevocationStore backed by Redis, suited for
+// multi-process kontrol deployments where every instance must observe a
+// revocation immediately.
+type RedisRevocationStore struct {
+	pool      *redis.Pool
+	keyPrefix string
+}
+
+// NewRedisRevocationStore returns a RedisRevocationStore that stores
+// revoked jti's as keys prefixed with keyPrefix (e.g. "kite:revoked:"),
+// relying on Redis' own TTL to expire them at the given until time.
+func NewRedisRevocationStore(pool *redis.Pool, keyPrefix string) *RedisRevocationStore {
+	return &RedisRevocationStore{pool: pool, keyPrefix: keyPrefix}
+}
+
+func (s *RedisRevocationStore) IsRevoked(jti string) (bool, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("EXISTS", s.keyPrefix+jti))
+}
+
+func (s *RedisRevocationStore) Revoke(jti string, until time.Time) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if until.IsZero() {
+		_, err := conn.Do("SET", s.keyPrefix+jti, until.Unix())
+		return err
+	}
+
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil // Already expired; nothing to store.
+	}
+
+	_, err := conn.Do("SET", s.keyPrefix+jti, until.Unix(), "EX", int(ttl.Seconds()))
+	return err
+}
+
+func (s *RedisRevocationStore) List() ([]RevokedToken, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", s.keyPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]RevokedToken, 0, len(keys))
+	for _, key := range keys {
+		v, err := redis.String(conn.Do("GET", key))
+		if err != nil {
+			continue
+		}
+
+		unix, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		tokens = append(tokens, RevokedToken{
+			JTI:   key[len(s.keyPrefix):],
+			Until: time.Unix(unix, 0),
+		})
+	}
+
+	return tokens, nil
+}
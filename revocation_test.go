@@ -0,0 +1,49 @@
+package kite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryRevocationStoreIsRevoked(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	if revoked, err := store.IsRevoked("never-seen"); err != nil || revoked {
+		t.Fatalf("IsRevoked(unknown jti) = %v, %v; want false, nil", revoked, err)
+	}
+
+	if err := store.Revoke("revoked-for-good", time.Time{}); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if revoked, err := store.IsRevoked("revoked-for-good"); err != nil || !revoked {
+		t.Fatalf("IsRevoked(revoked-for-good) = %v, %v; want true, nil", revoked, err)
+	}
+
+	if err := store.Revoke("revoked-until-future", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if revoked, err := store.IsRevoked("revoked-until-future"); err != nil || !revoked {
+		t.Fatalf("IsRevoked(revoked-until-future) = %v, %v; want true, nil", revoked, err)
+	}
+
+	if err := store.Revoke("revoked-until-past", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if revoked, err := store.IsRevoked("revoked-until-past"); err != nil || revoked {
+		t.Fatalf("IsRevoked(revoked-until-past) = %v, %v; want false, nil (revocation expired)", revoked, err)
+	}
+}
+
+func TestMemoryRevocationStoreList(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	store.Revoke("a", time.Time{})
+	store.Revoke("b", time.Now().Add(time.Hour))
+
+	tokens, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("List() returned %d tokens, want 2", len(tokens))
+	}
+}